@@ -0,0 +1,60 @@
+// Package formats describes a youtube.Format in a shape that's both
+// JSON-friendly and reusable for building human-readable quality labels, so
+// the HTML template and the JSON API read off the same values.
+package formats
+
+import (
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// FormatInfo is the machine-readable description of a single format.
+type FormatInfo struct {
+	ItagNo          int     `json:"itag"`
+	MimeType        string  `json:"mimeType"`
+	QualityLabel    string  `json:"qualityLabel"`
+	Bitrate         int     `json:"bitrate"`
+	FPS             int     `json:"fps,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	ContentLength   int64   `json:"contentLength"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	AudioChannels   int     `json:"audioChannels,omitempty"`
+	AudioSampleRate string  `json:"audioSampleRate,omitempty"`
+	AudioQuality    string  `json:"audioQuality,omitempty"`
+	URL             string  `json:"url,omitempty"`
+}
+
+// Describe builds a FormatInfo for f. duration is the owning video's total
+// length; it's used to estimate ContentLength for adaptive formats that
+// don't report one. Live streams, where duration is zero, are left with
+// ContentLength 0 rather than a nonsense estimate. The stream URL is only
+// included when includeURL is true, since it's a signed, expiring link that
+// most callers shouldn't need.
+func Describe(f *youtube.Format, duration time.Duration, includeURL bool) FormatInfo {
+	info := FormatInfo{
+		ItagNo:          f.ItagNo,
+		MimeType:        f.MimeType,
+		QualityLabel:    f.QualityLabel,
+		Bitrate:         f.Bitrate,
+		FPS:             f.FPS,
+		Width:           f.Width,
+		Height:          f.Height,
+		ContentLength:   f.ContentLength,
+		DurationSeconds: duration.Seconds(),
+		AudioChannels:   f.AudioChannels,
+		AudioSampleRate: f.AudioSampleRate,
+		AudioQuality:    f.AudioQuality,
+	}
+
+	if info.ContentLength == 0 && f.Bitrate > 0 && duration > 0 {
+		info.ContentLength = int64(float64(f.Bitrate/8) * duration.Seconds())
+	}
+
+	if includeURL {
+		info.URL = f.URL
+	}
+
+	return info
+}
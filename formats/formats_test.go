@@ -0,0 +1,86 @@
+package formats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+func TestDescribeDASHOnlyFormat(t *testing.T) {
+	f := &youtube.Format{
+		ItagNo:        137,
+		MimeType:      `video/mp4; codecs="avc1.640028"`,
+		QualityLabel:  "1080p",
+		Bitrate:       4500000,
+		FPS:           30,
+		Width:         1920,
+		Height:        1080,
+		ContentLength: 52428800,
+		AudioChannels: 0,
+	}
+
+	info := Describe(f, 120*time.Second, false)
+
+	if info.ItagNo != 137 {
+		t.Errorf("ItagNo = %d, want 137", info.ItagNo)
+	}
+	if info.AudioChannels != 0 {
+		t.Errorf("AudioChannels = %d, want 0 for a video-only DASH format", info.AudioChannels)
+	}
+	if info.ContentLength != f.ContentLength {
+		t.Errorf("ContentLength = %d, want the reported %d (no estimate needed)", info.ContentLength, f.ContentLength)
+	}
+	if info.URL != "" {
+		t.Errorf("URL = %q, want empty when includeURL is false", info.URL)
+	}
+}
+
+func TestDescribeEstimatesContentLengthWhenMissing(t *testing.T) {
+	f := &youtube.Format{
+		ItagNo:        140,
+		MimeType:      "audio/mp4",
+		Bitrate:       128000,
+		ContentLength: 0,
+		AudioChannels: 2,
+	}
+	duration := 100 * time.Second
+
+	info := Describe(f, duration, false)
+
+	want := int64(float64(f.Bitrate/8) * duration.Seconds())
+	if info.ContentLength != want {
+		t.Errorf("ContentLength = %d, want estimate %d", info.ContentLength, want)
+	}
+}
+
+func TestDescribeLiveStreamLeavesContentLengthZero(t *testing.T) {
+	f := &youtube.Format{
+		ItagNo:        95,
+		MimeType:      "video/mp4",
+		Bitrate:       2500000,
+		ContentLength: 0,
+	}
+
+	info := Describe(f, 0, false)
+
+	if info.ContentLength != 0 {
+		t.Errorf("ContentLength = %d, want 0 when duration is zero (live stream)", info.ContentLength)
+	}
+	if info.DurationSeconds != 0 {
+		t.Errorf("DurationSeconds = %v, want 0", info.DurationSeconds)
+	}
+}
+
+func TestDescribeIncludesURLWhenVerbose(t *testing.T) {
+	f := &youtube.Format{
+		ItagNo: 22,
+		URL:    "https://example.com/stream",
+	}
+
+	info := Describe(f, time.Minute, true)
+
+	if info.URL != f.URL {
+		t.Errorf("URL = %q, want %q when includeURL is true", info.URL, f.URL)
+	}
+}
@@ -1,226 +1,764 @@
-package main
-
-import (
-	"fmt"
-	"html/template"
-	"io"
-	"math/rand"
-	"net/http"
-	"strings"
-	"sync"
-
-	"github.com/kkdai/youtube/v2"
-)
-
-// VideoData holds video information
-type VideoData struct {
-	Image        string
-	Title        string
-	QualityVideo []string
-	QualityAudio []string
-	client       *youtube.Client
-	video        *youtube.Video
-	formatMap    map[string]*youtube.Format
-}
-
-// TemplateData holds color information for the template
-type TemplateData struct {
-	Color1 string
-	Color2 string
-}
-
-
-type SharedData struct {
-	sync.Mutex
-	m map[string]VideoData
-}
-
-var (
-	templateData     TemplateData
-	// videoData        VideoData
-	templates *template.Template
-	client           *youtube.Client
-	shared           SharedData
-	initOnce         sync.Once
-)
-
-// generateRandomColor generates a random color in hexadecimal format
-func generateRandomColor() string {
-	r := rand.Intn(256)
-	g := rand.Intn(256)
-	b := rand.Intn(256)
-	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
-}
-
-func getCookies() string {
-	charSet := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	key := ""
-	for i := 0; i < 8; i++ {
-		randomIndex := rand.Intn(len(charSet))
-		key += string(charSet[randomIndex])
-	}
-	return key
-}
-
-// initializeTemplates initializes the templates once
-func init() {
-	shared.m = make(map[string]VideoData) // Initialize the map in SharedData
-	templates = template.Must(template.ParseGlob("static/*.html")) // Pre-load templates
-}
-
-func renderTemplate(w http.ResponseWriter, tmpl string, data interface{}) {
-	err := templates.ExecuteTemplate(w, tmpl, data)
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-}
-
-
-// homeHandler handles the home page request
-func homeHandler(w http.ResponseWriter, r *http.Request) {
-	color1 := generateRandomColor()
-	color2 := generateRandomColor()
-	templateData.Color1 = color1
-	templateData.Color2 = color2
-	renderTemplate(w, "home.html", templateData)
-}
-
-// searchHandler handles the video search request
-func searchHandler(w http.ResponseWriter, r *http.Request) {
-	url := r.FormValue("url")
-	if url == "" {
-		http.Error(w, "URL parameter is required", http.StatusBadRequest)
-		return
-	}
-
-	video, err := client.GetVideo(url)
-	if err != nil {
-		http.Error(w, "Failed to fetch video: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	videoData := VideoData{
-		video:     video,
-		client:    client,
-		formatMap: make(map[string]*youtube.Format),
-	}
-
-	
-
-	for _, format := range video.Formats {
-		var description string
-		if format.AudioChannels > 0 {
-			if strings.Contains(format.MimeType, "video") {
-				if format.ContentLength == 0 {
-					sizeMB := float64((float64(format.Bitrate/8) * video.Duration.Seconds()) / 1048576.0)
-					description = fmt.Sprintf("%s (%.2fM)", format.QualityLabel, sizeMB)
-				} else {
-					sizeMB := float64(format.ContentLength) / 1048576.0
-					description = fmt.Sprintf("%s (%.2fM)", format.QualityLabel, sizeMB)
-				}
-			} else if strings.Contains(format.MimeType, "audio") {
-				if format.ContentLength == 0 {
-					sizeMB := float64((float64(format.Bitrate/8) * video.Duration.Seconds()) / 1048576.0)
-					description = fmt.Sprintf("%dkbps (%.2fM)", format.AverageBitrate/1000, sizeMB)
-				} else {
-					sizeMB := float64(format.ContentLength) / 1048576.0
-					description = fmt.Sprintf("%dkbps (%.2fM)", format.AverageBitrate/1000, sizeMB)
-				}
-			}
-			if strings.Contains(format.MimeType, "video") {
-				videoData.QualityVideo = append(videoData.QualityVideo, description)
-			} else if strings.Contains(format.MimeType, "audio") {
-				videoData.QualityAudio = append(videoData.QualityAudio, description)
-			}
-			videoData.formatMap[description] = &format
-		}
-
-	}
-
-	videoData.Image = video.Thumbnails[0].URL
-	videoData.Title = video.Title
-
-
-	cookies := r.Cookies()
-	key := ""
-	if len(cookies) == 0 {
-		key = getCookies()
-		cookie := http.Cookie{
-			Name:  "user",
-			Value: key,
-		}
-		http.SetCookie(w, &cookie)
-	}
-	shared.Lock()
-	shared.m[key] = videoData
-	shared.Unlock()
-
-	renderTemplate(w, "download.html",videoData )
-}
-
-// downloadHandler handles the video download request
-func downloadHandler(w http.ResponseWriter, r *http.Request) {
-
-
-	cookie, err := r.Cookie("user")
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-	shared.Lock()
-	videoData, _ := shared.m[cookie.Value]
-	shared.Unlock()
-
-	
-	quality := r.FormValue("Quality")
-	format, exists := videoData.formatMap[quality]
-	if !exists {
-		http.Error(w, "Unsupported format", http.StatusBadRequest)
-		return
-	}
-
-	stream, _, err := client.GetStream(videoData.video, format)
-	if err != nil {
-		http.Error(w, "Failed to get video stream: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	defer stream.Close()
-
-	var fileExtension, contentType string
-	if strings.Contains(quality, "kbps") {
-		fileExtension = ".mp3"
-		contentType = "audio/mpeg"
-	} else if strings.Contains(quality, "p") {
-		fileExtension = ".mp4"
-		contentType = "video/mp4"
-	} else {
-		http.Error(w, "Unsupported format", http.StatusBadRequest)
-		return
-	}
-
-	fileName := "download" + fileExtension
-	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
-	w.Header().Set("Content-Type", contentType)
-
-	if _, err := io.Copy(w, stream); err != nil {
-		http.Error(w, "Failed to write video stream to response: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-}
-
-// main starts the server and handles the routes
-func main() {
-	client = &youtube.Client{}
-
-	http.HandleFunc("/", homeHandler)
-	http.HandleFunc("/search", searchHandler)
-	http.HandleFunc("/download", downloadHandler)
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
-
-	fmt.Println("Starting server at :8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		fmt.Println("Failed to start server:", err)
-	}
-}
+package main
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/QOthman/Youtube-Downloader/formats"
+	"github.com/QOthman/Youtube-Downloader/muxer"
+	"github.com/QOthman/Youtube-Downloader/progress"
+	"github.com/QOthman/Youtube-Downloader/session"
+)
+
+// VideoData holds video information
+type VideoData struct {
+	Image        string
+	Title        string
+	QualityVideo []string
+	QualityAudio []string
+	client       *youtube.Client
+	video        *youtube.Video
+	formatMap    map[string]*youtube.Format
+	downloads    *session.Semaphore
+	progress     *progress.Tracker
+}
+
+// TemplateData holds color information for the template
+type TemplateData struct {
+	Color1 string
+	Color2 string
+}
+
+// PlaylistData holds the entries and quality presets shown on the playlist
+// selection page.
+type PlaylistData struct {
+	URL     string
+	Title   string
+	Presets []string
+	Entries []PlaylistEntryData
+}
+
+// PlaylistEntryData is a single selectable video on the playlist page.
+type PlaylistEntryData struct {
+	ID    string
+	Title string
+}
+
+// playlistQualityPresets are the uniform quality choices offered for a
+// whole playlist; resolvePlaylistFormat maps each one to a concrete format
+// per video.
+var playlistQualityPresets = []string{"1080p", "720p", "480p", "128kbps audio"}
+
+const (
+	sessionCookie          = "user"
+	sessionTTL             = 30 * time.Minute
+	sessionJanitorInterval = time.Minute
+	maxSessions            = 10000
+	// maxConcurrentDownloads is capped at 1: a session's progress.Tracker
+	// lives in a single VideoData.progress field, so two downloads running
+	// at once would clobber each other's tracker in the store and race on
+	// clearing it, leaving /progress reporting the wrong (or no) download.
+	maxConcurrentDownloads = 1
+	sessionSecretSize      = 32
+
+	// playlistWorkers bounds how many playlist entries are downloaded and
+	// muxed concurrently while building a ZIP archive.
+	playlistWorkers = 3
+)
+
+var (
+	templateData TemplateData
+	templates    *template.Template
+	client       *youtube.Client
+	store        session.Store
+	sessionKey   []byte
+)
+
+// generateRandomColor generates a random color in hexadecimal format
+func generateRandomColor() string {
+	r := mathrand.Intn(256)
+	g := mathrand.Intn(256)
+	b := mathrand.Intn(256)
+	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
+}
+
+// initializeTemplates initializes the templates once
+func init() {
+	store = session.NewMemoryStore(maxSessions, sessionJanitorInterval)
+
+	sessionKey = make([]byte, sessionSecretSize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		panic("failed to generate session signing key: " + err.Error())
+	}
+
+	templates = template.Must(template.ParseGlob("static/*.html")) // Pre-load templates
+}
+
+func renderTemplate(w http.ResponseWriter, tmpl string, data interface{}) {
+	err := templates.ExecuteTemplate(w, tmpl, data)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// homeHandler handles the home page request
+func homeHandler(w http.ResponseWriter, r *http.Request) {
+	color1 := generateRandomColor()
+	color2 := generateRandomColor()
+	templateData.Color1 = color1
+	templateData.Color2 = color2
+	renderTemplate(w, "home.html", templateData)
+}
+
+// bestAudioFormat picks the highest-bitrate audio track available, for
+// pairing with a video-only DASH format during muxing.
+func bestAudioFormat(formats youtube.FormatList) *youtube.Format {
+	var best *youtube.Format
+	for i := range formats {
+		f := &formats[i]
+		if f.AudioChannels == 0 {
+			continue
+		}
+		if best == nil || f.AverageBitrate > best.AverageBitrate {
+			best = f
+		}
+	}
+	return best
+}
+
+// audioContainer maps an audio MIME type to the short container name ffmpeg
+// expects for its "-f" demuxer flag.
+func audioContainer(mimeType string) string {
+	switch {
+	case strings.Contains(mimeType, "webm"):
+		return "webm"
+	case strings.Contains(mimeType, "mp4"):
+		return "mp4"
+	default:
+		return "webm"
+	}
+}
+
+// formatsAPIHandler handles GET /api/formats?url=...&verbose=1, returning
+// every available itag as JSON so the module can back CLIs, browser
+// extensions, or other non-HTML clients. Pass verbose=1 to include each
+// format's signed (and expiring) stream URL.
+func formatsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		http.Error(w, "url parameter is required", http.StatusBadRequest)
+		return
+	}
+	verbose := r.URL.Query().Get("verbose") == "1"
+
+	video, err := client.GetVideo(url)
+	if err != nil {
+		http.Error(w, "Failed to fetch video: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	infos := make([]formats.FormatInfo, 0, len(video.Formats))
+	for _, format := range video.Formats {
+		infos = append(infos, formats.Describe(&format, video.Duration, verbose))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(infos); err != nil {
+		http.Error(w, "Failed to encode formats: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// searchHandler handles the video search request
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	url := r.FormValue("url")
+	if url == "" {
+		http.Error(w, "URL parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	video, err := client.GetVideo(url)
+	if err != nil {
+		http.Error(w, "Failed to fetch video: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	videoData := VideoData{
+		video:     video,
+		client:    client,
+		formatMap: make(map[string]*youtube.Format),
+	}
+
+	for _, format := range video.Formats {
+		var description string
+		sizeMB := float64(formats.Describe(&format, video.Duration, false).ContentLength) / 1048576.0
+		switch {
+		case format.AudioChannels > 0 && strings.Contains(format.MimeType, "video"):
+			// Progressive format: video and audio already in one container.
+			description = fmt.Sprintf("%s (%.2fM)", format.QualityLabel, sizeMB)
+			videoData.QualityVideo = append(videoData.QualityVideo, description)
+		case format.AudioChannels > 0 && strings.Contains(format.MimeType, "audio"):
+			description = fmt.Sprintf("%dkbps (%.2fM)", format.AverageBitrate/1000, sizeMB)
+			videoData.QualityAudio = append(videoData.QualityAudio, description)
+		case format.AudioChannels == 0 && strings.Contains(format.MimeType, "video") && format.QualityLabel != "":
+			// Adaptive (DASH) video-only format: no audio track, needs muxing at download time.
+			description = fmt.Sprintf("%s (%.2fM) [DASH]", format.QualityLabel, sizeMB)
+			videoData.QualityVideo = append(videoData.QualityVideo, description)
+		}
+		if description != "" {
+			f := format
+			videoData.formatMap[description] = &f
+		}
+	}
+
+	videoData.Image = video.Thumbnails[0].URL
+	videoData.Title = video.Title
+
+	sessionID, ok := sessionIDFromRequest(r)
+	if !ok {
+		cookie, id, err := session.NewCookie(sessionCookie, sessionKey, sessionTTL, r.TLS != nil)
+		if err != nil {
+			http.Error(w, "Failed to create session: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(w, cookie)
+		sessionID = id
+	}
+
+	// Reuse the existing session's semaphore if one is already stored, so a
+	// re-search (e.g. a second tab, or a new search while a prior download is
+	// still streaming) doesn't hand out a fresh, non-exhausted semaphore that
+	// would let a second /download bypass maxConcurrentDownloads.
+	if existing, ok := store.Get(sessionID); ok {
+		videoData.downloads = existing.(VideoData).downloads
+	} else {
+		videoData.downloads = session.NewSemaphore(maxConcurrentDownloads)
+	}
+	store.Put(sessionID, videoData, sessionTTL)
+
+	renderTemplate(w, "download.html", videoData)
+}
+
+// sessionIDFromRequest extracts and verifies the signed session cookie from
+// r, returning ok=false if it's missing or fails verification.
+func sessionIDFromRequest(r *http.Request) (id string, ok bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", false
+	}
+	return session.Verify(sessionKey, cookie.Value)
+}
+
+// downloadHandler handles the video download request
+func downloadHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := sessionIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	value, ok := store.Get(sessionID)
+	if !ok {
+		http.Error(w, "Session expired, please search again", http.StatusUnauthorized)
+		return
+	}
+	videoData := value.(VideoData)
+
+	if !videoData.downloads.TryAcquire() {
+		http.Error(w, "Too many concurrent downloads for this session", http.StatusTooManyRequests)
+		return
+	}
+	defer videoData.downloads.Release()
+
+	quality := r.FormValue("Quality")
+	format, exists := videoData.formatMap[quality]
+	if !exists {
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	tracker := progress.NewTracker(0)
+	videoData.progress = tracker
+	store.Put(sessionID, videoData, sessionTTL)
+	defer func() {
+		tracker.Close()
+		videoData.progress = nil
+		store.Put(sessionID, videoData, sessionTTL)
+	}()
+
+	switch {
+	case strings.Contains(quality, "[DASH]"):
+		downloadDASHVideo(w, videoData, format, tracker)
+	case strings.Contains(quality, "kbps"):
+		downloadAudio(w, videoData, format, tracker)
+	case strings.Contains(quality, "p"):
+		downloadProgressive(w, videoData, format, tracker)
+	default:
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+	}
+}
+
+// downloadDASHVideo serves a video-only DASH format muxed with the best
+// available audio track into a single MP4. If ffmpeg isn't available it
+// falls back to streaming the video-only format with no audio, same as
+// before muxing support existed.
+func downloadDASHVideo(w http.ResponseWriter, videoData VideoData, format *youtube.Format, tracker *progress.Tracker) {
+	if !muxer.Available() {
+		streamRaw(w, videoData, format, ".mp4", "video/mp4", tracker)
+		return
+	}
+
+	audioFormat := bestAudioFormat(videoData.video.Formats)
+	if audioFormat == nil {
+		http.Error(w, "No audio track available to mux", http.StatusInternalServerError)
+		return
+	}
+
+	videoStream, _, err := client.GetStream(videoData.video, format)
+	if err != nil {
+		http.Error(w, "Failed to get video stream: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer videoStream.Close()
+
+	audioStream, _, err := client.GetStream(videoData.video, audioFormat)
+	if err != nil {
+		http.Error(w, "Failed to get audio stream: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer audioStream.Close()
+
+	videoInfo := formats.Describe(format, videoData.video.Duration, false)
+	audioInfo := formats.Describe(audioFormat, videoData.video.Duration, false)
+	tracker.SetTotal(videoInfo.ContentLength + audioInfo.ContentLength)
+
+	w.Header().Set("Content-Disposition", "attachment; filename=download.mp4")
+	w.Header().Set("Content-Type", "video/mp4")
+
+	trackedVideo := tracker.NewReader(videoStream)
+	trackedAudio := tracker.NewReader(audioStream)
+	if err := muxer.MuxToMP4(w, trackedVideo, trackedAudio); err != nil {
+		http.Error(w, "Failed to mux video: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// downloadAudio serves an audio-only format transcoded to a real MP3. If
+// ffmpeg isn't available it falls back to streaming the raw (mislabeled)
+// container, same as before transcoding support existed.
+func downloadAudio(w http.ResponseWriter, videoData VideoData, format *youtube.Format, tracker *progress.Tracker) {
+	stream, _, err := client.GetStream(videoData.video, format)
+	if err != nil {
+		http.Error(w, "Failed to get audio stream: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer stream.Close()
+
+	info := formats.Describe(format, videoData.video.Duration, false)
+	tracker.SetTotal(info.ContentLength)
+	trackedStream := tracker.NewReader(stream)
+
+	w.Header().Set("Content-Disposition", "attachment; filename=download.mp3")
+	w.Header().Set("Content-Type", "audio/mpeg")
+
+	if !muxer.Available() {
+		if _, err := io.Copy(w, trackedStream); err != nil {
+			http.Error(w, "Failed to write audio stream to response: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	bitrate := fmt.Sprintf("%dk", format.AverageBitrate/1000)
+	if err := muxer.TranscodeToMP3(w, trackedStream, audioContainer(format.MimeType), bitrate); err != nil {
+		http.Error(w, "Failed to transcode audio: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// downloadProgressive serves a format that already contains muxed video and
+// audio, straight from YouTube.
+func downloadProgressive(w http.ResponseWriter, videoData VideoData, format *youtube.Format, tracker *progress.Tracker) {
+	streamRaw(w, videoData, format, ".mp4", "video/mp4", tracker)
+}
+
+// streamRaw copies a single format's stream directly to the response.
+func streamRaw(w http.ResponseWriter, videoData VideoData, format *youtube.Format, fileExtension, contentType string, tracker *progress.Tracker) {
+	stream, _, err := client.GetStream(videoData.video, format)
+	if err != nil {
+		http.Error(w, "Failed to get video stream: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer stream.Close()
+
+	info := formats.Describe(format, videoData.video.Duration, false)
+	tracker.SetTotal(info.ContentLength)
+	trackedStream := tracker.NewReader(stream)
+
+	w.Header().Set("Content-Disposition", "attachment; filename=download"+fileExtension)
+	w.Header().Set("Content-Type", contentType)
+
+	if _, err := io.Copy(w, trackedStream); err != nil {
+		http.Error(w, "Failed to write video stream to response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// progressHandler streams download progress for the caller's session over
+// Server-Sent Events until the download finishes or the client disconnects.
+func progressHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := sessionIDFromRequest(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	value, ok := store.Get(sessionID)
+	if !ok {
+		http.Error(w, "Session expired, please search again", http.StatusUnauthorized)
+		return
+	}
+	videoData := value.(VideoData)
+	if videoData.progress == nil {
+		http.Error(w, "No download in progress", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for {
+		select {
+		case update, ok := <-videoData.progress.Updates():
+			if !ok {
+				return
+			}
+			var pct float64
+			if update.Total > 0 {
+				pct = 100 * float64(update.BytesRead) / float64(update.Total)
+			}
+			fmt.Fprintf(w, "event: progress\ndata: {\"pct\":%.1f,\"bps\":%.0f}\n\n", pct, update.BPS)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// playlistHandler handles the playlist search request, rendering a page
+// where the visitor picks a uniform quality and which entries to include.
+func playlistHandler(w http.ResponseWriter, r *http.Request) {
+	url := r.FormValue("url")
+	if url == "" {
+		http.Error(w, "URL parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	playlist, err := client.GetPlaylist(url)
+	if err != nil {
+		http.Error(w, "Failed to fetch playlist: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := PlaylistData{
+		URL:     url,
+		Title:   playlist.Title,
+		Presets: playlistQualityPresets,
+	}
+	for _, entry := range playlist.Videos {
+		data.Entries = append(data.Entries, PlaylistEntryData{ID: entry.ID, Title: entry.Title})
+	}
+
+	renderTemplate(w, "playlist.html", data)
+}
+
+// playlistDownloadHandler streams a ZIP archive containing the selected
+// playlist entries, each downloaded (and muxed or transcoded, as needed) at
+// the chosen uniform quality. Entries are fetched concurrently by a bounded
+// worker pool; a failing entry doesn't abort the archive, it's recorded as
+// a FAILED_<title>.txt entry instead.
+func playlistDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playlistURL := r.FormValue("url")
+	quality := r.FormValue("Quality")
+	videoIDs := r.Form["VideoID"]
+	if playlistURL == "" || quality == "" || len(videoIDs) == 0 {
+		http.Error(w, "url, Quality and at least one VideoID are required", http.StatusBadRequest)
+		return
+	}
+
+	playlist, err := client.GetPlaylist(playlistURL)
+	if err != nil {
+		http.Error(w, "Failed to fetch playlist: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entryByID := make(map[string]*youtube.PlaylistEntry, len(playlist.Videos))
+	for _, entry := range playlist.Videos {
+		entryByID[entry.ID] = entry
+	}
+
+	selected := make([]*youtube.PlaylistEntry, 0, len(videoIDs))
+	for _, id := range videoIDs {
+		if entry, ok := entryByID[id]; ok {
+			selected = append(selected, entry)
+		}
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=playlist.zip")
+	w.Header().Set("Content-Type", "application/zip")
+
+	if err := writePlaylistZip(w, selected, quality); err != nil {
+		fmt.Println("Failed to build playlist archive:", err)
+	}
+}
+
+// playlistResult is one playlist entry's download outcome, ready to be
+// written into the archive.
+type playlistResult struct {
+	title string
+	path  string // temp file holding the downloaded media; empty when err != nil
+	ext   string
+	err   error
+}
+
+// writePlaylistZip downloads each entry with up to playlistWorkers running
+// concurrently, then writes the results into a ZIP archive on w in arrival
+// order. zip.Writer isn't safe for concurrent use, so only the final
+// sequential write touches it.
+func writePlaylistZip(w io.Writer, entries []*youtube.PlaylistEntry, quality string) error {
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	results := make(chan *playlistResult, len(entries))
+	var g errgroup.Group
+	g.SetLimit(playlistWorkers)
+
+	for _, entry := range entries {
+		entry := entry
+		g.Go(func() error {
+			results <- downloadPlaylistEntry(entry, quality)
+			return nil
+		})
+	}
+	go func() {
+		g.Wait()
+		close(results)
+	}()
+
+	var firstErr error
+	for result := range results {
+		if firstErr != nil {
+			// Already failed: keep draining so every in-flight worker's temp
+			// file gets cleaned up instead of leaking on disk.
+			cleanupPlaylistResult(result)
+			continue
+		}
+		if err := appendPlaylistResult(zw, result); err != nil {
+			firstErr = err
+			cleanupPlaylistResult(result)
+		}
+	}
+	return firstErr
+}
+
+// cleanupPlaylistResult removes the temp file backing result, if any. It's
+// used when a result is discarded without going through
+// appendPlaylistResult, which otherwise owns that cleanup on the success
+// path.
+func cleanupPlaylistResult(result *playlistResult) {
+	if result.path != "" {
+		os.Remove(result.path)
+	}
+}
+
+// downloadPlaylistEntry resolves entry to a video and format at quality,
+// downloads it into a temp file, and reports the outcome. It never returns
+// an error directly: failures are carried in playlistResult.err so one bad
+// video doesn't stop the rest of the pool.
+func downloadPlaylistEntry(entry *youtube.PlaylistEntry, quality string) *playlistResult {
+	video, err := client.VideoFromPlaylistEntry(entry)
+	if err != nil {
+		return &playlistResult{title: entry.Title, err: fmt.Errorf("fetch video: %w", err)}
+	}
+
+	format, isAudio, err := resolvePlaylistFormat(video, quality)
+	if err != nil {
+		return &playlistResult{title: entry.Title, err: err}
+	}
+
+	tmp, err := os.CreateTemp("", "yt-playlist-*")
+	if err != nil {
+		return &playlistResult{title: entry.Title, err: fmt.Errorf("create temp file: %w", err)}
+	}
+	defer tmp.Close()
+
+	ext := ".mp4"
+	if isAudio {
+		ext = ".mp3"
+	}
+
+	if err := writePlaylistFormat(tmp, video, format, isAudio); err != nil {
+		os.Remove(tmp.Name())
+		return &playlistResult{title: entry.Title, err: err}
+	}
+
+	return &playlistResult{title: entry.Title, path: tmp.Name(), ext: ext}
+}
+
+// resolvePlaylistFormat maps a uniform quality preset to a concrete format
+// for video. Audio presets pick the best available audio track; video
+// presets prefer a progressive (already-muxed) format over a DASH
+// video-only one, since the latter needs an extra muxing pass per video.
+func resolvePlaylistFormat(video *youtube.Video, quality string) (format *youtube.Format, isAudio bool, err error) {
+	if strings.Contains(quality, "kbps") || strings.Contains(quality, "audio") {
+		f := bestAudioFormat(video.Formats)
+		if f == nil {
+			return nil, false, fmt.Errorf("no audio format available for %q", video.Title)
+		}
+		return f, true, nil
+	}
+
+	candidates := video.Formats.Type("video").Quality(quality)
+	if len(candidates) == 0 {
+		return nil, false, fmt.Errorf("no %s format available for %q", quality, video.Title)
+	}
+	for i := range candidates {
+		if candidates[i].AudioChannels > 0 {
+			return &candidates[i], false, nil
+		}
+	}
+	return &candidates[0], false, nil
+}
+
+// writePlaylistFormat downloads format for video into w, muxing in a
+// separate audio track (for DASH video-only formats) or transcoding to MP3
+// (for audio formats) when ffmpeg is available.
+func writePlaylistFormat(w io.Writer, video *youtube.Video, format *youtube.Format, isAudio bool) error {
+	if isAudio {
+		stream, _, err := client.GetStream(video, format)
+		if err != nil {
+			return fmt.Errorf("get audio stream: %w", err)
+		}
+		defer stream.Close()
+
+		if !muxer.Available() {
+			_, err := io.Copy(w, stream)
+			return err
+		}
+		bitrate := fmt.Sprintf("%dk", format.AverageBitrate/1000)
+		return muxer.TranscodeToMP3(w, stream, audioContainer(format.MimeType), bitrate)
+	}
+
+	if format.AudioChannels == 0 && muxer.Available() {
+		audioFormat := bestAudioFormat(video.Formats)
+		if audioFormat == nil {
+			return fmt.Errorf("no audio track available to mux for %q", video.Title)
+		}
+
+		videoStream, _, err := client.GetStream(video, format)
+		if err != nil {
+			return fmt.Errorf("get video stream: %w", err)
+		}
+		defer videoStream.Close()
+
+		audioStream, _, err := client.GetStream(video, audioFormat)
+		if err != nil {
+			return fmt.Errorf("get audio stream: %w", err)
+		}
+		defer audioStream.Close()
+
+		return muxer.MuxToMP4(w, videoStream, audioStream)
+	}
+
+	stream, _, err := client.GetStream(video, format)
+	if err != nil {
+		return fmt.Errorf("get video stream: %w", err)
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// appendPlaylistResult writes one playlist entry's outcome into zw: the
+// downloaded media on success, or a FAILED_<title>.txt note on failure.
+func appendPlaylistResult(zw *zip.Writer, result *playlistResult) error {
+	name := sanitizeFilename(result.title)
+
+	if result.err != nil {
+		entryWriter, err := zw.Create("FAILED_" + name + ".txt")
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(entryWriter, result.err.Error()+"\n")
+		return err
+	}
+	defer os.Remove(result.path)
+
+	f, err := os.Open(result.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entryWriter, err := zw.Create(name + result.ext)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entryWriter, f)
+	return err
+}
+
+// sanitizeFilename strips path separators from a video title so it can be
+// safely used as a ZIP entry name.
+func sanitizeFilename(name string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+	return replacer.Replace(name)
+}
+
+// main starts the server and handles the routes
+func main() {
+	client = &youtube.Client{}
+
+	if muxer.Available() {
+		fmt.Println("ffmpeg found: muxing and MP3 transcoding enabled")
+	} else {
+		fmt.Println("ffmpeg not found: falling back to single-stream downloads")
+	}
+
+	http.HandleFunc("/", homeHandler)
+	http.HandleFunc("/search", searchHandler)
+	http.HandleFunc("/download", downloadHandler)
+	http.HandleFunc("/progress", progressHandler)
+	http.HandleFunc("/api/formats", formatsAPIHandler)
+	http.HandleFunc("/playlist", playlistHandler)
+	http.HandleFunc("/playlist/download", playlistDownloadHandler)
+	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+
+	fmt.Println("Starting server at :8080")
+	if err := http.ListenAndServe(":8080", nil); err != nil {
+		fmt.Println("Failed to start server:", err)
+	}
+}
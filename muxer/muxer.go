@@ -0,0 +1,132 @@
+// Package muxer pipes separately downloaded video and audio streams through
+// ffmpeg so adaptive (DASH) YouTube formats can be served as a single,
+// correctly-labeled file.
+package muxer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ffmpegPath is the resolved location of the ffmpeg binary, or "" if it was
+// not found on PATH at startup.
+var ffmpegPath string
+
+func init() {
+	ffmpegPath, _ = exec.LookPath("ffmpeg")
+}
+
+// Available reports whether ffmpeg was found at startup. Callers should fall
+// back to serving a single raw stream when it returns false.
+func Available() bool {
+	return ffmpegPath != ""
+}
+
+// MuxToMP4 combines a video-only stream and an audio-only stream into a
+// single MP4 container, without re-encoding, and writes the result to w.
+//
+// ffmpeg needs seekable input to mux two streams, so both are first drained
+// to temporary files, concurrently so the slower of the two streams (not
+// their sum) sets the wait, and removed once muxing finishes.
+func MuxToMP4(w io.Writer, video, audio io.Reader) error {
+	if !Available() {
+		return fmt.Errorf("muxer: ffmpeg not available")
+	}
+
+	var videoFile, audioFile string
+	var g errgroup.Group
+	g.Go(func() error {
+		f, err := writeTemp("yt-video-*.mp4", video)
+		if err != nil {
+			return fmt.Errorf("muxer: buffering video stream: %w", err)
+		}
+		videoFile = f
+		return nil
+	})
+	g.Go(func() error {
+		f, err := writeTemp("yt-audio-*.m4a", audio)
+		if err != nil {
+			return fmt.Errorf("muxer: buffering audio stream: %w", err)
+		}
+		audioFile = f
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		if videoFile != "" {
+			os.Remove(videoFile)
+		}
+		if audioFile != "" {
+			os.Remove(audioFile)
+		}
+		return err
+	}
+	defer os.Remove(videoFile)
+	defer os.Remove(audioFile)
+
+	cmd := exec.Command(ffmpegPath,
+		"-y",
+		"-i", videoFile,
+		"-i", audioFile,
+		"-c", "copy",
+		"-f", "mp4",
+		"-movflags", "frag_keyframe+empty_moov",
+		"pipe:1",
+	)
+	cmd.Stdout = w
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("muxer: ffmpeg mux failed: %w (%s)", err, stderr.String())
+	}
+	return nil
+}
+
+// TranscodeToMP3 reads a single audio stream, in the given container format
+// (e.g. "webm", "mp4"), and transcodes it to a true MP3 at bitrate (e.g.
+// "128k"), writing the result to w.
+func TranscodeToMP3(w io.Writer, src io.Reader, srcFormat, bitrate string) error {
+	if !Available() {
+		return fmt.Errorf("muxer: ffmpeg not available")
+	}
+
+	cmd := exec.Command(ffmpegPath,
+		"-f", srcFormat,
+		"-i", "pipe:0",
+		"-vn",
+		"-c:a", "libmp3lame",
+		"-b:a", bitrate,
+		"-f", "mp3",
+		"pipe:1",
+	)
+	cmd.Stdin = src
+	cmd.Stdout = w
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("muxer: ffmpeg transcode failed: %w (%s)", err, stderr.String())
+	}
+	return nil
+}
+
+// writeTemp drains r into a new temporary file matching pattern and returns
+// its path.
+func writeTemp(pattern string, r io.Reader) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,121 @@
+// Package progress tracks bytes read across one or more concurrent streams
+// (e.g. a muxed download's separate video and audio tracks) and publishes
+// aggregated throughput updates for an SSE handler to forward to the
+// browser.
+package progress
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// ewmaAlpha weights how quickly the reported throughput reacts to the most
+// recent read versus the running average. Higher is twitchier.
+const ewmaAlpha = 0.3
+
+// Update is a point-in-time snapshot of a tracked download.
+type Update struct {
+	BytesRead int64
+	Total     int64
+	BPS       float64 // EWMA bytes/sec
+}
+
+// Tracker aggregates progress across every reader created with NewReader
+// and publishes combined Updates to a buffered channel. It's sized for one
+// download at a time, which is the per-session case this package is used
+// for.
+type Tracker struct {
+	total int64
+	ch    chan Update
+
+	mu       sync.Mutex
+	read     []int64
+	bps      float64
+	lastRead time.Time
+}
+
+// NewTracker returns a Tracker expecting total bytes across all readers it
+// will be asked to wrap.
+func NewTracker(total int64) *Tracker {
+	return &Tracker{
+		total: total,
+		ch:    make(chan Update, 1),
+	}
+}
+
+// Updates returns the channel Updates are published to. It's closed when
+// Close is called.
+func (t *Tracker) Updates() <-chan Update {
+	return t.ch
+}
+
+// SetTotal updates the expected total byte count. Callers that don't know
+// the total up front (e.g. they still need to resolve a second stream to
+// mux) can create the Tracker early and set this once it's known.
+func (t *Tracker) SetTotal(total int64) {
+	t.mu.Lock()
+	t.total = total
+	t.mu.Unlock()
+}
+
+// NewReader wraps r so every read it serves counts towards this tracker's
+// aggregate progress.
+func (t *Tracker) NewReader(r io.Reader) io.Reader {
+	t.mu.Lock()
+	idx := len(t.read)
+	t.read = append(t.read, 0)
+	t.mu.Unlock()
+	return &trackedReader{r: r, tracker: t, idx: idx}
+}
+
+// Close releases the Updates channel. Callers must stop using the tracker
+// afterwards.
+func (t *Tracker) Close() {
+	close(t.ch)
+}
+
+func (t *Tracker) record(idx int, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if !t.lastRead.IsZero() {
+		if dt := now.Sub(t.lastRead).Seconds(); dt > 0 {
+			instant := float64(n) / dt
+			if t.bps == 0 {
+				t.bps = instant
+			} else {
+				t.bps = ewmaAlpha*instant + (1-ewmaAlpha)*t.bps
+			}
+		}
+	}
+	t.lastRead = now
+	t.read[idx] += int64(n)
+
+	var sum int64
+	for _, r := range t.read {
+		sum += r
+	}
+
+	select {
+	case t.ch <- Update{BytesRead: sum, Total: t.total, BPS: t.bps}:
+	default:
+		// The SSE handler isn't keeping up; it'll catch up on the next read.
+	}
+}
+
+// trackedReader reports every successful Read to its owning Tracker.
+type trackedReader struct {
+	r       io.Reader
+	tracker *Tracker
+	idx     int
+}
+
+func (tr *trackedReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		tr.tracker.record(tr.idx, n)
+	}
+	return n, err
+}
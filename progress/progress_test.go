@@ -0,0 +1,48 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestTrackerAggregatesMultipleReaders(t *testing.T) {
+	tracker := NewTracker(10)
+	defer tracker.Close()
+
+	video := tracker.NewReader(bytes.NewReader([]byte("hello")))
+	if _, err := io.ReadAll(video); err != nil {
+		t.Fatalf("reading video: %v", err)
+	}
+	<-tracker.Updates() // drain the video-only update before starting audio
+
+	audio := tracker.NewReader(bytes.NewReader([]byte("world")))
+	if _, err := io.ReadAll(audio); err != nil {
+		t.Fatalf("reading audio: %v", err)
+	}
+	final := <-tracker.Updates()
+
+	if final.BytesRead != 10 {
+		t.Errorf("BytesRead = %d, want 10 (5 bytes from each reader)", final.BytesRead)
+	}
+	if final.Total != 10 {
+		t.Errorf("Total = %d, want 10", final.Total)
+	}
+}
+
+func TestTrackerSetTotal(t *testing.T) {
+	tracker := NewTracker(0)
+	defer tracker.Close()
+
+	tracker.SetTotal(100)
+
+	r := tracker.NewReader(bytes.NewReader([]byte("x")))
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+
+	update := <-tracker.Updates()
+	if update.Total != 100 {
+		t.Errorf("Total = %d, want 100", update.Total)
+	}
+}
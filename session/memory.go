@@ -0,0 +1,126 @@
+package session
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// entry is the payload kept in each list.Element.
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// MemoryStore is an in-memory Store bounded by both entry count (LRU
+// eviction) and per-entry TTL (background janitor sweep).
+type MemoryStore struct {
+	mu       sync.Mutex
+	ll       *list.List // front = most recently used
+	items    map[string]*list.Element
+	maxItems int
+	done     chan struct{}
+}
+
+// NewMemoryStore returns a MemoryStore holding at most maxItems entries
+// (0 means unbounded), sweeping expired entries every janitorInterval.
+func NewMemoryStore(maxItems int, janitorInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		maxItems: maxItems,
+		done:     make(chan struct{}),
+	}
+	go s.janitor(janitorInterval)
+	return s
+}
+
+func (s *MemoryStore) Get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		s.removeElement(el)
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (s *MemoryStore) Put(key string, value interface{}, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := s.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value = value
+		e.expiresAt = expiresAt
+		s.ll.MoveToFront(el)
+		return
+	}
+
+	el := s.ll.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	s.items[key] = el
+
+	if s.maxItems > 0 && s.ll.Len() > s.maxItems {
+		s.removeElement(s.ll.Back())
+	}
+}
+
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		s.removeElement(el)
+	}
+}
+
+// Close stops the janitor goroutine. The store must not be used afterwards.
+func (s *MemoryStore) Close() {
+	close(s.done)
+}
+
+// removeElement drops el from both the list and the lookup map. Callers
+// must hold s.mu.
+func (s *MemoryStore) removeElement(el *list.Element) {
+	e := el.Value.(*entry)
+	delete(s.items, e.key)
+	s.ll.Remove(el)
+}
+
+func (s *MemoryStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sweep()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// sweep removes every expired entry. The list is kept in recency order, not
+// expiry order, so a full scan is required.
+func (s *MemoryStore) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for el := s.ll.Front(); el != nil; {
+		next := el.Next()
+		if now.After(el.Value.(*entry).expiresAt) {
+			s.removeElement(el)
+		}
+		el = next
+	}
+}
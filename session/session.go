@@ -0,0 +1,130 @@
+// Package session provides a bounded, TTL-based store for per-visitor state,
+// signed session cookies, and a per-session concurrency limiter.
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Store is a TTL-bounded key/value store for per-session data. Entries that
+// outlive their TTL are no longer returned by Get, though the store may
+// reclaim their memory lazily.
+//
+// This package intentionally ships only MemoryStore. A Redis-backed Store
+// was prototyped for multi-instance deployments and deliberately dropped
+// rather than fixed: this app's session value (main.go's VideoData) embeds
+// an open *youtube.Client/*youtube.Video pair, a Semaphore, and a
+// progress.Tracker, none of which have a meaningful representation outside
+// the process that created them — round-tripping them through JSON (or any
+// other codec) would either silently lose the unexported fields
+// (formatMap, downloads, progress) or, if exported, hand back dead
+// placeholders that can't stream or limit anything. A real shared store
+// needs VideoData split into a serializable session payload (video ID,
+// chosen formats) plus in-flight download state that's inherently
+// per-instance, which is a bigger change than a Store backend swap. Until
+// that split happens, running more than one instance needs session
+// affinity (sticky sessions) at the load balancer.
+type Store interface {
+	// Get returns the value stored under key, or ok=false if it is missing
+	// or has expired.
+	Get(key string) (value interface{}, ok bool)
+	// Put stores value under key, replacing any existing entry, and resets
+	// its TTL.
+	Put(key string, value interface{}, ttl time.Duration)
+	// Delete removes key, if present.
+	Delete(key string)
+	// Close stops any background goroutines owned by the store.
+	Close()
+}
+
+// NewID returns a cryptographically random session identifier.
+func NewID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Sign produces a tamper-evident cookie value for id, combining it with an
+// HMAC-SHA256 computed under secret so a client can't forge or guess another
+// session's ID.
+func Sign(secret []byte, id string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return id + "." + sig
+}
+
+// Verify checks a cookie value produced by Sign and returns the session ID
+// it carries, or ok=false if the value is malformed or the signature
+// doesn't match.
+func Verify(secret []byte, value string) (id string, ok bool) {
+	parts := strings.SplitN(value, ".", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", false
+	}
+	expected := Sign(secret, parts[0])
+	if !hmac.Equal([]byte(expected), []byte(value)) {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// NewCookie mints a fresh session ID and wraps it in a signed, HttpOnly,
+// SameSite=Lax cookie named name that expires after ttl. secure should be
+// true only when the request it's attached to arrived over TLS: a Secure
+// cookie set on a plain-HTTP response is dropped by the browser (and by
+// net/http's own cookiejar), so hardcoding it true would silently break
+// every session on a server that isn't terminating TLS itself.
+func NewCookie(name string, secret []byte, ttl time.Duration, secure bool) (cookie *http.Cookie, id string, err error) {
+	id, err = NewID()
+	if err != nil {
+		return nil, "", err
+	}
+	cookie = &http.Cookie{
+		Name:     name,
+		Value:    Sign(secret, id),
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	return cookie, id, nil
+}
+
+// Semaphore bounds how many operations a single session may have in flight
+// at once, e.g. parallel /download requests, so one visitor can't exhaust
+// server resources.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore returns a Semaphore that allows up to n concurrent holders.
+func NewSemaphore(n int) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, n)}
+}
+
+// TryAcquire reserves a slot without blocking and reports whether one was
+// available.
+func (s *Semaphore) TryAcquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot reserved by TryAcquire.
+func (s *Semaphore) Release() {
+	<-s.slots
+}